@@ -0,0 +1,68 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"fmt"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cloudnative-pg/cloudnative-pg/internal/cmd/plugin"
+)
+
+// Clients bundles the two client handles every collector in this package
+// needs: a controller-runtime client for typed List/Get calls against the
+// CloudNativePG CRDs and core resources, and the REST config used to build
+// the ad-hoc typed clientsets needed for logs, metrics and metrics.k8s.io.
+//
+// Collectors used to read the plugin.Client / plugin.Config package
+// globals directly, which only ever pointed at one kubeconfig context.
+// Threading a Clients value explicitly lets the report command build a
+// fresh pair per context and aggregate several clusters into one ZIP.
+type Clients struct {
+	Client client.Client
+	Config *rest.Config
+}
+
+// defaultClients wraps the plugin package's global client and REST config,
+// i.e. the kubeconfig context `kubectl` resolved for this invocation
+func defaultClients() *Clients {
+	return &Clients{Client: plugin.Client, Config: plugin.Config}
+}
+
+// NewClientsForContext builds a Clients pair for kubeContext. An empty
+// kubeContext resolves to the current context, same as defaultClients.
+func NewClientsForContext(kubeContext string) (*Clients, error) {
+	configFlags := genericclioptions.NewConfigFlags(true)
+	if kubeContext != "" {
+		configFlags.Context = &kubeContext
+	}
+
+	config, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not build REST config for context '%s': %w", kubeContext, err)
+	}
+
+	cl, err := client.New(config, client.Options{Scheme: plugin.Scheme})
+	if err != nil {
+		return nil, fmt.Errorf("could not build client for context '%s': %w", kubeContext, err)
+	}
+
+	return &Clients{Client: cl, Config: config}, nil
+}
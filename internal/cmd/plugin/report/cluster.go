@@ -0,0 +1,166 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cloudnative-pg/cloudnative-pg/internal/cmd/plugin"
+)
+
+// NewClusterCmd creates the `report cluster` subcommand, which bundles
+// logs, metrics, resource usage and configuration for a single Cluster
+// into a ZIP file
+func NewClusterCmd() *cobra.Command {
+	var (
+		outputFile   string
+		since        string
+		until        string
+		tailLines    int64
+		previous     bool
+		concurrency  int
+		includeDrift bool
+		contexts     []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cluster [cluster-name]",
+		Short: "Report cluster logs, metrics and configuration into a ZIP file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterName := args[0]
+
+			options, err := buildPodLogOptions(since, until, tailLines, previous)
+			if err != nil {
+				return err
+			}
+
+			return runClusterReport(cmd.Context(), clusterName, options, concurrency, includeDrift, contexts, outputFile)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "file", "f", "",
+		`output ZIP file (default "<cluster-name>-report.zip")`)
+	cmd.Flags().StringVar(&since, "since", "",
+		"only return logs newer than a relative duration (e.g. 5s, 2m, 3h) or an RFC3339 timestamp")
+	cmd.Flags().StringVar(&until, "until", "",
+		"only return logs older than a relative duration or an RFC3339 timestamp")
+	cmd.Flags().Int64Var(&tailLines, "tail", -1,
+		"number of lines from the end of the logs to collect per container (-1 for all)")
+	cmd.Flags().BoolVar(&previous, "previous", false,
+		"also collect logs from the previously terminated container instance, if any")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 0,
+		"number of pods to collect logs from concurrently (default min(8, pod count))")
+	// Drift detection is inherently Cluster-scoped (it re-renders each
+	// resource from a Cluster's spec), so --include-drift only makes sense
+	// here; this package has no `report operator` subcommand to wire it
+	// into as well (see the package doc comment in report.go).
+	cmd.Flags().BoolVar(&includeDrift, "include-drift", false,
+		"also compare the cluster's live resources against what the operator would render from its spec")
+	cmd.Flags().StringSliceVar(&contexts, "contexts", nil,
+		"kubeconfig contexts to collect the report from (default: the current context only); "+
+			"bundles one directory per context, useful for a replica cluster spread across clusters")
+
+	return cmd
+}
+
+// buildPodLogOptions translates the `report cluster` log flags into a
+// PodLogOptions
+func buildPodLogOptions(since, until string, tailLines int64, previous bool) (PodLogOptions, error) {
+	options := PodLogOptions{Previous: previous}
+
+	if tailLines >= 0 {
+		options.TailLines = &tailLines
+	}
+
+	if since != "" {
+		t, err := parseLogBoundary(since)
+		if err != nil {
+			return options, fmt.Errorf("invalid --since: %w", err)
+		}
+		options.Since = t
+	}
+
+	if until != "" {
+		t, err := parseLogBoundary(until)
+		if err != nil {
+			return options, fmt.Errorf("invalid --until: %w", err)
+		}
+		options.Until = t
+	}
+
+	return options, nil
+}
+
+// parseLogBoundary parses a --since/--until value, accepting either a
+// relative duration (interpreted as "ago") or an absolute RFC3339
+// timestamp
+func parseLogBoundary(value string) (*metav1.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		t := metav1.NewTime(time.Now().Add(-d))
+		return &t, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("must be a duration (e.g. 5s, 2m, 3h) or an RFC3339 timestamp: %w", err)
+	}
+	t := metav1.NewTime(parsed)
+	return &t, nil
+}
+
+// runClusterReport collects the report for clusterName into outputFile,
+// once per entry in contexts (or just the current context when contexts is
+// empty)
+func runClusterReport(
+	ctx context.Context, clusterName string, options PodLogOptions, concurrency int, includeDrift bool,
+	contexts []string, outputFile string,
+) error {
+	if outputFile == "" {
+		outputFile = fmt.Sprintf("%s-report.zip", clusterName)
+	}
+
+	zipFile, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("could not create '%s': %w", outputFile, err)
+	}
+	defer func() {
+		_ = zipFile.Close()
+	}()
+
+	zipper := zip.NewWriter(zipFile)
+	defer func() {
+		_ = zipper.Close()
+	}()
+
+	opts := ClusterReportOptions{
+		ClusterName:  clusterName,
+		Namespace:    plugin.Namespace,
+		LogOptions:   options,
+		Concurrency:  concurrency,
+		IncludeDrift: includeDrift,
+	}
+
+	return CollectMultiContext(ctx, contexts, opts, zipper)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// defaultLogCollectionConcurrency is used whenever the caller does not pick
+// an explicit --concurrency value
+const defaultLogCollectionConcurrency = 8
+
+// logUnit is a single log file to be collected: one container of one pod,
+// optionally its previously terminated instance. path is the destination
+// of the file inside the ZIP.
+type logUnit struct {
+	pod       corev1.Pod
+	container string
+	previous  bool
+	path      string
+}
+
+// appendPodLogUnits appends to units the logUnits needed to collect every
+// container's logs of pod, plus the previous-instance logs when requested
+// and available
+func appendPodLogUnits(units []logUnit, pod corev1.Pod, logsdir string, options PodLogOptions) []logUnit {
+	for _, container := range pod.Spec.Containers {
+		units = append(units, logUnit{
+			pod:       pod,
+			container: container.Name,
+			path:      filepath.Join(logsdir, fmt.Sprintf("%s-%s.jsonl", pod.Name, container.Name)),
+		})
+
+		if options.Previous && hasPreviousTermination(pod, container.Name) {
+			units = append(units, logUnit{
+				pod:       pod,
+				container: container.Name,
+				previous:  true,
+				path: filepath.Join(logsdir,
+					fmt.Sprintf("%s-%s-previous.jsonl", pod.Name, container.Name)),
+			})
+		}
+	}
+	return units
+}
+
+// concurrencyFor returns concurrency if it is a positive number, otherwise
+// it defaults to min(defaultLogCollectionConcurrency, numUnits)
+func concurrencyFor(concurrency, numUnits int) int {
+	if concurrency > 0 {
+		return concurrency
+	}
+	if numUnits < defaultLogCollectionConcurrency {
+		if numUnits < 1 {
+			return 1
+		}
+		return numUnits
+	}
+	return defaultLogCollectionConcurrency
+}
+
+// collectLogUnitsToZip streams every unit's logs with a bounded worker
+// pool, each into its own scratch file on disk, then copies the scratch
+// files into zipper one by one, in the same order as units, since
+// *zip.Writer is not safe for concurrent use.
+//
+// Each worker closes its scratch file as soon as it finishes writing to
+// it, and the serialization pass reopens it for reading: this keeps the
+// number of simultaneously open file descriptors bounded by concurrency,
+// not by the total number of units.
+//
+// Collection is cancelled as soon as any unit fails. Progress is reported
+// to stderr as units complete.
+func collectLogUnitsToZip(
+	ctx context.Context, clients *Clients, units []logUnit, options PodLogOptions, concurrency int, zipper *zip.Writer,
+) error {
+	if len(units) == 0 {
+		return nil
+	}
+
+	scratchPaths := make([]string, len(units))
+	defer func() {
+		for _, path := range scratchPaths {
+			if path != "" {
+				_ = os.Remove(path)
+			}
+		}
+	}()
+
+	// the default worker count is bounded by the number of pods, not by the
+	// number of units, of which a pod can have several
+	pods := make(map[string]struct{}, len(units))
+	for _, unit := range units {
+		pods[unit.pod.Name] = struct{}{}
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrencyFor(concurrency, len(pods)))
+
+	var completed int32
+	for i := range units {
+		group.Go(func() (err error) {
+			unit := units[i]
+
+			f, err := os.CreateTemp("", "cnpg-report-log-*")
+			if err != nil {
+				return fmt.Errorf("could not create scratch file for '%s': %w", unit.path, err)
+			}
+			scratchPaths[i] = f.Name()
+			defer func() {
+				if closeErr := f.Close(); err == nil && closeErr != nil {
+					err = closeErr
+				}
+			}()
+
+			unitOptions := options
+			unitOptions.Previous = unit.previous
+			if err := streamPodLogs(groupCtx, clients, unit.pod, unit.container, unitOptions, f); err != nil {
+				return fmt.Errorf("could not collect logs for '%s': %w", unit.path, err)
+			}
+
+			n := atomic.AddInt32(&completed, 1)
+			fmt.Fprintf(os.Stderr, "collected logs for %s (%d/%d)\n", unit.path, n, len(units))
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return err
+	}
+
+	for i, unit := range units {
+		if err := copyScratchFileToZip(scratchPaths[i], unit.path, zipper); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyScratchFileToZip reopens the scratch file at path, which its
+// producer already closed once collection finished, and copies it into
+// the zip at entryPath
+func copyScratchFileToZip(path, entryPath string, zipper *zip.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not reopen scratch file for '%s': %w", entryPath, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	writer, err := zipper.Create(entryPath)
+	if err != nil {
+		return fmt.Errorf("could not add '%s' to zip: %w", entryPath, err)
+	}
+	if _, err := io.Copy(writer, f); err != nil {
+		return fmt.Errorf("could not write '%s' to zip: %w", entryPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("concurrencyFor", func() {
+	It("uses the explicit value when positive", func() {
+		Expect(concurrencyFor(3, 100)).To(Equal(3))
+	})
+
+	It("defaults to the unit count when it is below the default cap", func() {
+		Expect(concurrencyFor(0, 4)).To(Equal(4))
+	})
+
+	It("defaults to the cap when the unit count exceeds it", func() {
+		Expect(concurrencyFor(0, 50)).To(Equal(defaultLogCollectionConcurrency))
+	})
+
+	It("never returns less than one", func() {
+		Expect(concurrencyFor(0, 0)).To(Equal(1))
+	})
+})
@@ -0,0 +1,346 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	apiv1 "github.com/cloudnative-pg/cloudnative-pg/api/v1"
+	"github.com/cloudnative-pg/cloudnative-pg/pkg/specs"
+	"github.com/cloudnative-pg/cloudnative-pg/pkg/specs/pgbouncer"
+)
+
+// defaultMonitoringConfigMapName is the name of the cluster-wide default
+// set of monitoring queries, same as the one asserted on in the metrics
+// e2e suite
+const defaultMonitoringConfigMapName = "cnpg-default-monitoring"
+
+// driftTarget is one resource owned by a Cluster that can be compared
+// against what the operator would render from the Cluster's spec.
+//
+// expected is nil for resources pkg/specs has no deterministic constructor
+// for (e.g. generated Secrets, or PVCs whose content depends on runtime
+// status): for those, only the live object is captured.
+type driftTarget struct {
+	// name identifies the resource inside the drift/ folder, e.g.
+	// "service-rw" or "pdb-primary"
+	name     string
+	key      client.ObjectKey
+	expected client.Object
+	live     client.Object
+}
+
+// collectDriftToZip fetches the live state of the resources owned by
+// cluster, re-renders the ones pkg/specs can deterministically produce from
+// cluster's spec, and writes expected.yaml, live.yaml and a unified.diff
+// for each into a drift/ section of the zip. This gives an immediate
+// answer to "did someone edit this resource by hand?" without running the
+// operator's reconciler.
+func collectDriftToZip(
+	ctx context.Context, clients *Clients, cluster apiv1.Cluster, dirname string, zipper *zip.Writer,
+) error {
+	driftdir := filepath.Join(dirname, "drift")
+	if _, err := zipper.Create(driftdir + "/"); err != nil {
+		return fmt.Errorf("could not add '%s' to zip: %w", driftdir, err)
+	}
+
+	targets := driftTargets(cluster)
+
+	poolerTargets, err := poolerDriftTargets(ctx, clients, cluster)
+	if err != nil {
+		return err
+	}
+	targets = append(targets, poolerTargets...)
+
+	for _, target := range targets {
+		if err := collectOneDrift(ctx, clients, driftdir, target, zipper); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// poolerDriftTargets lists every Pooler referencing cluster and adds its
+// expected/live Deployment to the comparison. Unlike the fixed-name
+// resources in driftTargets, Poolers are discovered rather than derived
+// from cluster's name, since a cluster can have zero or more of them.
+func poolerDriftTargets(ctx context.Context, clients *Clients, cluster apiv1.Cluster) ([]driftTarget, error) {
+	var poolerList apiv1.PoolerList
+	if err := clients.Client.List(ctx, &poolerList, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, fmt.Errorf("could not list poolers: %w", err)
+	}
+
+	var targets []driftTarget
+	for i := range poolerList.Items {
+		pooler := poolerList.Items[i]
+		if pooler.Spec.Cluster.Name != cluster.Name {
+			continue
+		}
+
+		targets = append(targets, driftTarget{
+			name:     "pooler-" + pooler.Name,
+			key:      client.ObjectKey{Namespace: pooler.Namespace, Name: pooler.Name},
+			expected: pgbouncer.Deployment(&pooler, &cluster),
+			live:     &appsv1.Deployment{},
+		})
+	}
+
+	return targets, nil
+}
+
+// driftTargets lists the resources to compare for cluster. Live objects
+// are fetched lazily by collectOneDrift; here we only need their kind and
+// key, plus the expected object when one can be rendered.
+func driftTargets(cluster apiv1.Cluster) []driftTarget {
+	namespace := cluster.Namespace
+
+	targets := []driftTarget{
+		{
+			name:     "service-rw",
+			key:      client.ObjectKey{Namespace: namespace, Name: cluster.GetServiceReadWriteName()},
+			expected: specs.CreateClusterReadWriteService(&cluster),
+			live:     &corev1.Service{},
+		},
+		{
+			name:     "service-ro",
+			key:      client.ObjectKey{Namespace: namespace, Name: cluster.GetServiceReadOnlyName()},
+			expected: specs.CreateClusterReadOnlyService(&cluster),
+			live:     &corev1.Service{},
+		},
+		{
+			name:     "service-r",
+			key:      client.ObjectKey{Namespace: namespace, Name: cluster.GetServiceReadName()},
+			expected: specs.CreateClusterReadService(&cluster),
+			live:     &corev1.Service{},
+		},
+		{
+			name:     "pdb-primary",
+			key:      client.ObjectKey{Namespace: namespace, Name: cluster.Name + "-primary"},
+			expected: specs.BuildPrimaryPodDisruptionBudget(&cluster),
+			live:     &policyv1.PodDisruptionBudget{},
+		},
+		{
+			name:     "pdb-replica",
+			key:      client.ObjectKey{Namespace: namespace, Name: cluster.Name},
+			expected: specs.BuildReplicasPodDisruptionBudget(&cluster),
+			live:     &policyv1.PodDisruptionBudget{},
+		},
+	}
+
+	// pkg/specs has no deterministic constructor for instance Pods, PVCs or
+	// generated Secrets: their content legitimately depends on runtime
+	// status or randomness, so we only capture the live object, with no
+	// expected to diff against. The same applies to the default monitoring
+	// ConfigMap, added below, which is free text a user may have edited.
+	for _, instanceName := range cluster.Status.InstanceNames {
+		targets = append(targets,
+			driftTarget{
+				name: "pod-" + instanceName,
+				key:  client.ObjectKey{Namespace: namespace, Name: instanceName},
+				live: &corev1.Pod{},
+			},
+			driftTarget{
+				name: "pvc-" + instanceName,
+				key:  client.ObjectKey{Namespace: namespace, Name: instanceName},
+				live: &corev1.PersistentVolumeClaim{},
+			},
+		)
+	}
+
+	referencedSecrets := []string{
+		cluster.GetSuperuserSecretName(),
+		cluster.GetApplicationSecretName(),
+		cluster.GetReplicationSecretName(),
+		cluster.GetServerSecretName(),
+		cluster.GetClientCASecretName(),
+		cluster.GetServerCASecretName(),
+	}
+	for _, secretName := range referencedSecrets {
+		targets = append(targets, driftTarget{
+			name: "secret-" + secretName,
+			key:  client.ObjectKey{Namespace: namespace, Name: secretName},
+			live: &corev1.Secret{},
+		})
+	}
+
+	targets = append(targets, driftTarget{
+		name: "configmap-" + defaultMonitoringConfigMapName,
+		key:  client.ObjectKey{Namespace: namespace, Name: defaultMonitoringConfigMapName},
+		live: &corev1.ConfigMap{},
+	})
+
+	return targets
+}
+
+// collectOneDrift fetches target's live object (when it still exists) and
+// writes live.yaml under driftdir/name, plus expected.yaml and unified.diff
+// when target has an expected object
+func collectOneDrift(
+	ctx context.Context, clients *Clients, driftdir string, target driftTarget, zipper *zip.Writer,
+) error {
+	targetDir := filepath.Join(driftdir, target.name)
+
+	notFound := false
+	if err := clients.Client.Get(ctx, target.key, target.live); err != nil {
+		if !apierrs.IsNotFound(err) {
+			return fmt.Errorf("could not get '%s': %w", target.key, err)
+		}
+		notFound = true
+	}
+
+	var expectedYAML, liveYAML []byte
+	var err error
+	if target.expected != nil {
+		if expectedYAML, err = yaml.Marshal(target.expected); err != nil {
+			return fmt.Errorf("could not render expected '%s': %w", target.name, err)
+		}
+	}
+	if !notFound {
+		if liveYAML, err = yaml.Marshal(target.live); err != nil {
+			return fmt.Errorf("could not render live '%s': %w", target.name, err)
+		}
+	} else {
+		liveYAML = []byte(fmt.Sprintf("# %s: resource not found\n", target.key))
+	}
+
+	if len(expectedYAML) > 0 {
+		if err := writeZipFile(zipper, filepath.Join(targetDir, "expected.yaml"), expectedYAML); err != nil {
+			return err
+		}
+	}
+	if err := writeZipFile(zipper, filepath.Join(targetDir, "live.yaml"), liveYAML); err != nil {
+		return err
+	}
+	if len(expectedYAML) > 0 {
+		diff := unifiedDiff("expected.yaml", "live.yaml", string(expectedYAML), string(liveYAML))
+		if err := writeZipFile(zipper, filepath.Join(targetDir, "unified.diff"), []byte(diff)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeZipFile adds a single file with the given content to the zip
+func writeZipFile(zipper *zip.Writer, path string, content []byte) error {
+	writer, err := zipper.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not add '%s' to zip: %w", path, err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		return fmt.Errorf("could not write '%s': %w", path, err)
+	}
+	return nil
+}
+
+// unifiedDiff produces a minimal line-based unified diff between a and b,
+// enough to eyeball a hand-edited field without pulling in a diff library
+func unifiedDiff(aName, bName, a, b string) string {
+	aLines := strings.Split(a, "\n")
+	bLines := strings.Split(b, "\n")
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+
+	for _, op := range diffLines(aLines, bLines) {
+		switch op.kind {
+		case diffEqual:
+			// unchanged lines don't show up in a unified diff
+		case diffRemoved:
+			fmt.Fprintf(&out, "-%s\n", op.line)
+		case diffAdded:
+			fmt.Fprintf(&out, "+%s\n", op.line)
+		}
+	}
+
+	return out.String()
+}
+
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffRemoved
+	diffAdded
+)
+
+// diffOp is one line of a diffLines result
+type diffOp struct {
+	kind diffOpKind
+	line string
+}
+
+// diffLines compares aLines against bLines using their longest common
+// subsequence, so that lines inserted or removed near the top don't shift
+// every following line out of alignment and show up as spurious changes.
+func diffLines(aLines, bLines []string) []diffOp {
+	n, m := len(aLines), len(bLines)
+
+	// lcsLen[i][j] is the length of the LCS of aLines[i:] and bLines[j:]
+	lcsLen := make([][]int, n+1)
+	for i := range lcsLen {
+		lcsLen[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcsLen[i][j] = lcsLen[i+1][j+1] + 1
+			} else if lcsLen[i+1][j] >= lcsLen[i][j+1] {
+				lcsLen[i][j] = lcsLen[i+1][j]
+			} else {
+				lcsLen[i][j] = lcsLen[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case aLines[i] == bLines[j]:
+			ops = append(ops, diffOp{kind: diffEqual, line: aLines[i]})
+			i++
+			j++
+		case lcsLen[i+1][j] >= lcsLen[i][j+1]:
+			ops = append(ops, diffOp{kind: diffRemoved, line: aLines[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: diffAdded, line: bLines[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{kind: diffRemoved, line: aLines[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{kind: diffAdded, line: bLines[j]})
+	}
+
+	return ops
+}
@@ -0,0 +1,48 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("unifiedDiff", func() {
+	It("produces no changed lines for identical input", func() {
+		content := "a\nb\nc"
+		diff := unifiedDiff("expected.yaml", "live.yaml", content, content)
+		Expect(diff).To(Equal("--- expected.yaml\n+++ live.yaml\n"))
+	})
+
+	It("does not cascade a single inserted line into a full-file diff", func() {
+		a := strings.Join([]string{"a", "b", "c", "d"}, "\n")
+		b := strings.Join([]string{"inserted", "a", "b", "c", "d"}, "\n")
+
+		diff := unifiedDiff("expected.yaml", "live.yaml", a, b)
+		Expect(diff).To(Equal("--- expected.yaml\n+++ live.yaml\n+inserted\n"))
+	})
+
+	It("reports both sides of a genuinely changed line", func() {
+		a := strings.Join([]string{"a", "b", "c"}, "\n")
+		b := strings.Join([]string{"a", "x", "c"}, "\n")
+
+		diff := unifiedDiff("expected.yaml", "live.yaml", a, b)
+		Expect(diff).To(Equal("--- expected.yaml\n+++ live.yaml\n-b\n+x\n"))
+	})
+})
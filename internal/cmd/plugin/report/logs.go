@@ -18,30 +18,63 @@ package report
 
 import (
 	"archive/zip"
+	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"path/filepath"
+	"strings"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/cloudnative-pg/cloudnative-pg/internal/cmd/plugin"
 	"github.com/cloudnative-pg/cloudnative-pg/pkg/utils"
 )
 
 const jobMatcherLabel = "job-name"
 
-// streamPodLogs streams the pod logs via REST to an io.Writer
+// PodLogOptions configures which slice of a container's log stream
+// streamPodLogs collects. It mirrors the fields of corev1.PodLogOptions
+// that are meaningful for a report, plus Until, which Kubernetes has no
+// server-side support for.
+type PodLogOptions struct {
+	// Since only returns the logs produced after this time
+	Since *metav1.Time
+	// Until stops the stream at the first log line produced after this
+	// time. As the Kubernetes API has no server-side equivalent, it is
+	// enforced by parsing timestamps client-side
+	Until *metav1.Time
+	// TailLines, if set, shows only the last N lines
+	TailLines *int64
+	// Previous collects the logs of the previously terminated container
+	// instance instead of the current one
+	Previous bool
+}
+
+func (o PodLogOptions) toPodLogOptions(container string) *corev1.PodLogOptions {
+	return &corev1.PodLogOptions{
+		Container:  container,
+		SinceTime:  o.Since,
+		TailLines:  o.TailLines,
+		Previous:   o.Previous,
+		Timestamps: o.Until != nil,
+	}
+}
+
+// streamPodLogs streams a single container's logs via REST to an io.Writer
 // in JSON-lines format
 //
 // NOTE: streaming to a writer is advantageous, as logs may take up a lot of
 // memory and blow up RAM if read/written in full to a buffer
-func streamPodLogs(ctx context.Context, pod corev1.Pod, writer io.Writer) (err error) {
-	pods := kubernetes.NewForConfigOrDie(plugin.Config).CoreV1().Pods(pod.Namespace)
-	logsRequest := pods.GetLogs(pod.Name, &corev1.PodLogOptions{})
+func streamPodLogs(
+	ctx context.Context, clients *Clients, pod corev1.Pod, container string, options PodLogOptions, writer io.Writer,
+) (err error) {
+	pods := kubernetes.NewForConfigOrDie(clients.Config).CoreV1().Pods(pod.Namespace)
+	logsRequest := pods.GetLogs(pod.Name, options.toPodLogOptions(container))
 	logStream, err := logsRequest.Stream(ctx)
 	if err != nil {
 		return fmt.Errorf("could not stream the logs: %w", err)
@@ -53,41 +86,63 @@ func streamPodLogs(ctx context.Context, pod corev1.Pod, writer io.Writer) (err e
 		}
 	}()
 
-	_, err = io.Copy(writer, logStream)
+	if options.Until != nil {
+		err = copyLogsUntil(writer, logStream, options.Until.Time)
+	} else {
+		_, err = io.Copy(writer, logStream)
+	}
 	if err != nil {
 		err = fmt.Errorf("could not send logs to writer: %w", err)
 	}
 	return err
 }
 
-// streamPodLogsToZip streams the pod logs to a new section in the ZIP
-func streamPodLogsToZip(ctx context.Context, pods []corev1.Pod,
-	dirname, name string, zipper *zip.Writer,
-) error {
-	logsdir := filepath.Join(dirname, name)
-	if _, err := zipper.Create(logsdir + "/"); err != nil {
-		return fmt.Errorf("could not add '%s' to zip: %w", logsdir, err)
-	}
-
-	for i := range pods {
-		pod := pods[i]
-		path := filepath.Join(logsdir, fmt.Sprintf("%s-logs.jsonl", pod.Name))
-		writer, zipperErr := zipper.Create(path)
-		if zipperErr != nil {
-			return fmt.Errorf("could not add '%s' to zip: %w", path, zipperErr)
+// copyLogsUntil copies the log lines read from src into dst, stopping as
+// soon as a line timestamped after until is found.
+//
+// src is expected to carry the timestamp Kubernetes injects when
+// PodLogOptions.Timestamps is set (an RFC3339Nano prefix followed by a
+// space); that prefix is stripped before writing so the persisted
+// <pod>-<container>.jsonl file stays valid JSON-lines, same as when no
+// cutoff is requested
+func copyLogsUntil(dst io.Writer, src io.Reader, until time.Time) error {
+	scanner := bufio.NewScanner(src)
+	// log lines can be arbitrarily long, so grow past bufio's small default
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		timestamp, message, found := strings.Cut(line, " ")
+		if found {
+			if parsed, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+				if parsed.After(until) {
+					return nil
+				}
+				line = message
+			}
 		}
-		if err := streamPodLogs(ctx, pod, writer); err != nil {
+		if _, err := fmt.Fprintln(dst, line); err != nil {
 			return err
 		}
 	}
+	return scanner.Err()
+}
 
-	return nil
+// hasPreviousTermination reports whether the given container has a
+// previously terminated instance whose logs `--previous` could retrieve
+func hasPreviousTermination(pod corev1.Pod, container string) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			return status.LastTerminationState.Terminated != nil
+		}
+	}
+	return false
 }
 
-// streamClusterLogsToZip streams the logs from the pods in the cluster, one by
-// one, each in a new file, within  a folder
-func streamClusterLogsToZip(ctx context.Context, clusterName, namespace string,
-	dirname string, zipper *zip.Writer,
+// streamClusterLogsToZip streams the logs from the pods in the cluster,
+// with one file per container, within a folder. Pods are collected
+// concurrently through a bounded worker pool; see collectLogUnitsToZip.
+func streamClusterLogsToZip(ctx context.Context, clients *Clients, clusterName, namespace string,
+	options PodLogOptions, concurrency int, dirname string, zipper *zip.Writer,
 ) error {
 	logsdir := filepath.Join(dirname, "logs")
 	_, err := zipper.Create(logsdir + "/")
@@ -100,31 +155,25 @@ func streamClusterLogsToZip(ctx context.Context, clusterName, namespace string,
 	}
 
 	var podList corev1.PodList
-	err = plugin.Client.List(ctx, &podList, matchClusterName, client.InNamespace(namespace))
+	err = clients.Client.List(ctx, &podList, matchClusterName, client.InNamespace(namespace))
 	if err != nil {
 		return fmt.Errorf("could not get cluster pods: %w", err)
 	}
 
+	var units []logUnit
 	for _, pod := range podList.Items {
-		writer, err := zipper.Create(filepath.Join(logsdir, pod.Name) + ".jsonl")
-		if err != nil {
-			return fmt.Errorf("could not add '%s' to zip: %w",
-				filepath.Join(logsdir, pod.Name), err)
-		}
-
-		err = streamPodLogs(ctx, pod, writer)
-		if err != nil {
-			return err
-		}
+		units = appendPodLogUnits(units, pod, logsdir, options)
 	}
 
-	return nil
+	return collectLogUnitsToZip(ctx, clients, units, options, concurrency, zipper)
 }
 
-// streamClusterJobLogsToZip checks for jobs in the cluster, and streams
-// the logs from the pods created by those jobs, one by one, each in a new file
-func streamClusterJobLogsToZip(ctx context.Context, clusterName, namespace string,
-	dirname string, zipper *zip.Writer,
+// streamClusterJobLogsToZip checks for jobs in the cluster, and streams the
+// logs from the pods created by those jobs, with one file per container.
+// Pods are collected concurrently through a bounded worker pool; see
+// collectLogUnitsToZip.
+func streamClusterJobLogsToZip(ctx context.Context, clients *Clients, clusterName, namespace string,
+	options PodLogOptions, concurrency int, dirname string, zipper *zip.Writer,
 ) error {
 	logsdir := filepath.Join(dirname, "job-logs")
 	_, err := zipper.Create(logsdir + "/")
@@ -137,34 +186,26 @@ func streamClusterJobLogsToZip(ctx context.Context, clusterName, namespace strin
 	}
 
 	var jobList batchv1.JobList
-	err = plugin.Client.List(ctx, &jobList, matchClusterName, client.InNamespace(namespace))
+	err = clients.Client.List(ctx, &jobList, matchClusterName, client.InNamespace(namespace))
 	if err != nil {
 		return fmt.Errorf("could not get cluster jobs: %w", err)
 	}
 
+	var units []logUnit
 	for _, job := range jobList.Items {
 		matchJobName := client.MatchingLabels{
 			jobMatcherLabel: job.Name,
 		}
 		var podList corev1.PodList
-		err = plugin.Client.List(ctx, &podList, matchJobName, client.InNamespace(namespace))
+		err = clients.Client.List(ctx, &podList, matchJobName, client.InNamespace(namespace))
 		if err != nil {
 			return fmt.Errorf("could not get pods for job '%s': %w", job.Name, err)
 		}
 
 		for _, pod := range podList.Items {
-			writer, err := zipper.Create(filepath.Join(logsdir, pod.Name) + ".jsonl")
-			if err != nil {
-				return fmt.Errorf("could not add '%s' to zip: %w",
-					filepath.Join(logsdir, pod.Name), err)
-			}
-
-			err = streamPodLogs(ctx, pod, writer)
-			if err != nil {
-				return err
-			}
+			units = appendPodLogUnits(units, pod, logsdir, options)
 		}
 	}
 
-	return nil
+	return collectLogUnitsToZip(ctx, clients, units, options, concurrency, zipper)
 }
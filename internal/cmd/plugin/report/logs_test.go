@@ -0,0 +1,58 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("copyLogsUntil", func() {
+	cutoff := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	It("stops copying at the first line timestamped after the cutoff", func() {
+		input := strings.Join([]string{
+			`2024-01-01T11:59:58.000000000Z {"msg":"before"}`,
+			`2024-01-01T12:00:01.000000000Z {"msg":"after"}`,
+			`2024-01-01T12:00:02.000000000Z {"msg":"also after"}`,
+		}, "\n")
+
+		var out bytes.Buffer
+		Expect(copyLogsUntil(&out, strings.NewReader(input), cutoff)).To(Succeed())
+		Expect(out.String()).To(Equal("{\"msg\":\"before\"}\n"))
+	})
+
+	It("strips the injected timestamp so the output stays valid JSONL", func() {
+		input := `2024-01-01T11:00:00.000000000Z {"msg":"hello"}` + "\n"
+
+		var out bytes.Buffer
+		Expect(copyLogsUntil(&out, strings.NewReader(input), cutoff)).To(Succeed())
+		Expect(out.String()).To(Equal("{\"msg\":\"hello\"}\n"))
+	})
+
+	It("passes through lines with no parseable timestamp unchanged", func() {
+		input := "not a timestamp at all\n"
+
+		var out bytes.Buffer
+		Expect(copyLogsUntil(&out, strings.NewReader(input), cutoff)).To(Succeed())
+		Expect(out.String()).To(Equal("not a timestamp at all\n"))
+	})
+})
@@ -0,0 +1,120 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cloudnative-pg/cloudnative-pg/pkg/utils"
+)
+
+// metricsExporterPort is the port where the instance manager exposes the
+// Prometheus metrics endpoint
+const metricsExporterPort = 9187
+
+// streamPodMetrics proxies a request to the metrics exporter running in the
+// given pod and streams the scraped Prometheus exposition format to writer
+//
+// NOTE: we go through the API server's pod proxy subresource rather than
+// opening a port-forward, so this works the same way whether `kubectl cnpg
+// report` is run from inside or outside the cluster network
+func streamPodMetrics(ctx context.Context, clients *Clients, pod corev1.Pod, writer io.Writer) (err error) {
+	pods := kubernetes.NewForConfigOrDie(clients.Config).CoreV1().Pods(pod.Namespace)
+	metricsStream, err := pods.
+		ProxyGet("http", pod.Name, strconv.Itoa(metricsExporterPort), "/metrics", nil).
+		Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("could not scrape metrics: %w", err)
+	}
+	defer func() {
+		innerErr := metricsStream.Close()
+		if err == nil && innerErr != nil {
+			err = innerErr
+		}
+	}()
+
+	_, err = io.Copy(writer, metricsStream)
+	if err != nil {
+		err = fmt.Errorf("could not send metrics to writer: %w", err)
+	}
+	return err
+}
+
+// streamClusterMetricsToZip streams a Prometheus metrics snapshot from every
+// Ready pod in the cluster, one by one, each in a new file, within a folder.
+//
+// Pods that are not Ready are skipped, since the exporter endpoint would not
+// be reachable: this is recorded in an errors.txt manifest alongside the
+// snapshots, rather than failing the whole report
+func streamClusterMetricsToZip(ctx context.Context, clients *Clients, clusterName, namespace string,
+	dirname string, zipper *zip.Writer,
+) error {
+	metricsdir := filepath.Join(dirname, "metrics")
+	if _, err := zipper.Create(metricsdir + "/"); err != nil {
+		return fmt.Errorf("could not add '%s' to zip: %w", metricsdir, err)
+	}
+
+	matchClusterName := client.MatchingLabels{
+		utils.ClusterLabelName: clusterName,
+	}
+
+	var podList corev1.PodList
+	if err := clients.Client.List(ctx, &podList, matchClusterName, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("could not get cluster pods: %w", err)
+	}
+
+	var collectionErrors strings.Builder
+	for _, pod := range podList.Items {
+		if !utils.IsPodReady(pod) {
+			fmt.Fprintf(&collectionErrors, "%s: pod is not Ready, skipped\n", pod.Name)
+			continue
+		}
+
+		path := filepath.Join(metricsdir, fmt.Sprintf("%s-metrics.prom", pod.Name))
+		writer, err := zipper.Create(path)
+		if err != nil {
+			return fmt.Errorf("could not add '%s' to zip: %w", path, err)
+		}
+
+		if err := streamPodMetrics(ctx, clients, pod, writer); err != nil {
+			fmt.Fprintf(&collectionErrors, "%s: %s\n", pod.Name, err)
+		}
+	}
+
+	if collectionErrors.Len() > 0 {
+		errPath := filepath.Join(metricsdir, "errors.txt")
+		errWriter, err := zipper.Create(errPath)
+		if err != nil {
+			return fmt.Errorf("could not add '%s' to zip: %w", errPath, err)
+		}
+		if _, err := io.WriteString(errWriter, collectionErrors.String()); err != nil {
+			return fmt.Errorf("could not write '%s': %w", errPath, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("contextDirs", func() {
+	It("collects a single root-level report when no contexts are given", func() {
+		Expect(contextDirs(nil)).To(Equal([]contextDir{{kubeContext: "", dirname: ""}}))
+	})
+
+	It("names each directory after its context", func() {
+		Expect(contextDirs([]string{"prod", "dr"})).To(Equal([]contextDir{
+			{kubeContext: "prod", dirname: "prod"},
+			{kubeContext: "dr", dirname: "dr"},
+		}))
+	})
+
+	It("falls back to current-context for an explicitly empty context", func() {
+		Expect(contextDirs([]string{""})).To(Equal([]contextDir{
+			{kubeContext: "", dirname: "current-context"},
+		}))
+	})
+})
@@ -0,0 +1,37 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package report implements the `kubectl cnpg report` commands, bundling
+// logs, metrics and configuration of a single Cluster into a ZIP file.
+//
+// Upstream `kubectl cnpg` also ships a `report operator` subcommand for
+// operator-wide diagnostics; it isn't part of this package and --include-drift
+// (see NewClusterCmd) is therefore only reachable from `report cluster`.
+package report
+
+import "github.com/spf13/cobra"
+
+// NewCmd creates the "report" command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report cluster resources, logs and configuration",
+	}
+
+	cmd.AddCommand(NewClusterCmd())
+
+	return cmd
+}
@@ -0,0 +1,129 @@
+/*
+Copyright The CloudNativePG Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cloudnative-pg/cloudnative-pg/pkg/utils"
+)
+
+// streamClusterResourceUsageToZip fetches the PodMetrics of the cluster's
+// pods and the NodeMetrics of the nodes hosting them from the
+// metrics.k8s.io API (normally backed by metrics-server), and writes them
+// as JSON into a resource-usage folder in the zip.
+//
+// Since metrics-server is an optional cluster add-on, the metrics API group
+// may not be registered: in that case an unavailable.txt marker is written
+// instead, so the rest of the report is unaffected.
+func streamClusterResourceUsageToZip(ctx context.Context, clients *Clients, clusterName, namespace string,
+	dirname string, zipper *zip.Writer,
+) error {
+	resourceUsageDir := filepath.Join(dirname, "resource-usage")
+	if _, err := zipper.Create(resourceUsageDir + "/"); err != nil {
+		return fmt.Errorf("could not add '%s' to zip: %w", resourceUsageDir, err)
+	}
+
+	metricsClient := metricsclientset.NewForConfigOrDie(clients.Config)
+
+	if _, err := metricsClient.Discovery().ServerResourcesForGroupVersion(metricsv1beta1.SchemeGroupVersion.String()); err != nil {
+		return writeUnavailableMarker(resourceUsageDir, err, zipper)
+	}
+
+	matchClusterName := client.MatchingLabels{
+		utils.ClusterLabelName: clusterName,
+	}
+	var podList corev1.PodList
+	if err := clients.Client.List(ctx, &podList, matchClusterName, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("could not get cluster pods: %w", err)
+	}
+
+	podMetrics := make([]metricsv1beta1.PodMetrics, 0, len(podList.Items))
+	nodeNames := make(map[string]struct{})
+	for _, pod := range podList.Items {
+		metrics, err := metricsClient.MetricsV1beta1().PodMetricses(namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("could not get metrics for pod '%s': %w", pod.Name, err)
+		}
+		podMetrics = append(podMetrics, *metrics)
+		if pod.Spec.NodeName != "" {
+			nodeNames[pod.Spec.NodeName] = struct{}{}
+		}
+	}
+
+	nodeMetrics := make([]metricsv1beta1.NodeMetrics, 0, len(nodeNames))
+	for nodeName := range nodeNames {
+		metrics, err := metricsClient.MetricsV1beta1().NodeMetricses().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("could not get metrics for node '%s': %w", nodeName, err)
+		}
+		nodeMetrics = append(nodeMetrics, *metrics)
+	}
+
+	if err := writeJSONToZip(zipper, filepath.Join(resourceUsageDir, "pods.json"), podMetrics); err != nil {
+		return err
+	}
+	if err := writeJSONToZip(zipper, filepath.Join(resourceUsageDir, "nodes.json"), nodeMetrics); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeUnavailableMarker records that the metrics API was not reachable,
+// without failing the report generation
+func writeUnavailableMarker(resourceUsageDir string, cause error, zipper *zip.Writer) error {
+	path := filepath.Join(resourceUsageDir, "unavailable.txt")
+	writer, err := zipper.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not add '%s' to zip: %w", path, err)
+	}
+	_, err = io.WriteString(writer,
+		fmt.Sprintf("metrics.k8s.io API is not available on this cluster: %s\n", cause))
+	if err != nil {
+		return fmt.Errorf("could not write '%s': %w", path, err)
+	}
+	return nil
+}
+
+// writeJSONToZip marshals v as indented JSON and writes it to path inside
+// the zip
+func writeJSONToZip(zipper *zip.Writer, path string, v any) error {
+	writer, err := zipper.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not add '%s' to zip: %w", path, err)
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("could not write '%s': %w", path, err)
+	}
+
+	return nil
+}